@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"go/build"
 	"io/ioutil"
-	"os/exec"
 	"path"
 	"strings"
+	"time"
 )
 
 type builder struct {
@@ -16,7 +16,9 @@ type builder struct {
 
 	term        Terminal
 	scm         Scm
+	log         *Logger
 	projectPath string
+	commitSHA   string
 	pack        *build.Package
 }
 
@@ -41,96 +43,164 @@ type buildConfig struct {
 
 	PreBuildCommands  []string
 	PostBuildCommands []string
+
+	Container containerConfig
+	CacheDir  string
 }
 
 type deployConfig struct {
 	DeployPath string
 	BinaryName string
+
+	Hosts       []string
+	User        string
+	Concurrency int
+
+	Checksum checksumConfig
 }
 
 var context = build.Default
 
-func newBuilder(config string) *builder {
+func newBuilder(config string) (*builder, error) {
 	if config == "" {
 		config = "./build.cfg"
 	}
 
 	f, err := ioutil.ReadFile(config)
-
 	if err != nil {
-		panic("Failed to read: " + config)
+		return nil, fmt.Errorf("failed to read %s: %w", config, err)
 	}
 
 	b := new(builder)
+	b.log = NewLogger(LogFormat)
 
 	err = json.Unmarshal(f, b)
-
 	if err != nil {
-		panic("Failed to parse " + config + ": " + err.Error())
+		return nil, fmt.Errorf("failed to parse %s: %w", config, err)
 	}
 
+	var hostTerm Terminal
 	if isHostLocal(b.BuildConfig.Host) {
-		b.term = new(LocalTerminal)
+		hostTerm = new(LocalTerminal)
 	} else {
 		sshClient := new(SSHConn)
-		b.term = sshClient
+		hostTerm = sshClient
 		sshClient.Connect(b.BuildConfig.Host, b.BuildConfig.User)
 	}
 
-	b.validatePackage()
+	if b.BuildConfig.Container.Image != "" {
+		b.term = NewContainerTerminal(hostTerm, b.BuildConfig.Jail, b.BuildConfig.Container)
+	} else {
+		b.term = hostTerm
+	}
+
+	if err := b.validatePackage(); err != nil {
+		return nil, err
+	}
 
-	return b
+	return b, nil
 }
 
-func Build(config string) {
-	b := newBuilder(config)
-	b.performBuild()
-	b.term.Close()
+func Build(config string) error {
+	b, err := newBuilder(config)
+	if err != nil {
+		return err
+	}
+	defer b.term.Close()
+
+	return b.performBuild()
 }
 
-func Deploy(config string) {
-	b := newBuilder(config)
-	b.deploy([]string{"localhost"})
-	b.term.Close()
+func Deploy(config string) error {
+	b, err := newBuilder(config)
+	if err != nil {
+		return err
+	}
+	defer b.term.Close()
+
+	hosts := b.DeployConfig.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+
+	results := b.deploy(hosts)
+
+	var failed []string
+	for _, r := range results {
+		if r.Success {
+			b.log.Info("deploy", "deployed to "+r.Host)
+		} else {
+			b.log.Error("deploy", "failed to deploy to "+r.Host+": "+r.Err.Error())
+			failed = append(failed, r.Host)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("deploy failed on: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
 }
 
-func (b *builder) performBuild() {
-	b.setupScm()
+func (b *builder) performBuild() error {
+	if err := b.setupScm(); err != nil {
+		return err
+	}
+
+	if !b.validateBuildEnvironment() {
+		return fmt.Errorf("build environment failed validation")
+	}
 
-	if b.validateBuildEnvironment() {
-		b.updateCode()
+	if err := b.updateCode(); err != nil {
+		return err
+	}
 
+	if b.BuildConfig.Container.Image == "" {
+		// A container build brings its own Go toolchain and GOPATH; the
+		// host's GoRoot almost certainly doesn't exist inside the image.
 		b.term.SetEnv("GOPATH", b.goPath())
 		b.term.SetEnv("GOROOT", b.BuildConfig.GoRoot)
-		b.term.SetEnv("CGO_CFLAGS", b.BuildConfig.CgoCFlags)
-		b.term.SetEnv("CGO_LDFLAGS", b.BuildConfig.CgoLdFlags)
+	}
+	b.term.SetEnv("CGO_CFLAGS", b.BuildConfig.CgoCFlags)
+	b.term.SetEnv("CGO_LDFLAGS", b.BuildConfig.CgoLdFlags)
 
-		b.runCommands(b.BuildConfig.PreBuildCommands)
+	if err := b.runCommands("pre-build-commands", b.BuildConfig.PreBuildCommands); err != nil {
+		return err
+	}
 
-		b.updateDependencies()
+	if err := b.updateDependencies(); err != nil {
+		return err
+	}
 
-		b.buildProject()
+	if err := b.buildProject(); err != nil {
+		return err
+	}
 
-		if b.BuildConfig.RunTests {
-			b.runTests()
-		}
+	if err := b.signArtifact(); err != nil {
+		return err
+	}
 
-		b.runCommands(b.BuildConfig.PostBuildCommands)
+	if b.BuildConfig.RunTests {
+		if err := b.runTests(); err != nil {
+			return err
+		}
 	}
+
+	return b.runCommands("post-build-commands", b.BuildConfig.PostBuildCommands)
 }
 
-func (b *builder) validatePackage() {
-	// Validate this package is a command
+func (b *builder) validatePackage() error {
 	var err error
 	b.pack, err = context.ImportDir(".", 0)
-
 	if err != nil {
-		panic("Could not import package for validation")
+		return fmt.Errorf("could not import package for validation: %w", err)
 	}
 
 	if !b.pack.IsCommand() {
-		panic("Package is not a command")
+		return fmt.Errorf("package is not a command")
 	}
+
+	return nil
 }
 
 // Ensure all directories exist
@@ -141,28 +211,38 @@ func (b *builder) validateBuildEnvironment() (valid bool) {
 	// Validate Jail exists
 	_, err = b.term.Exec("ls " + b.BuildConfig.Jail)
 	if err != nil {
-		fmt.Println("Could not find Jail directory: " + err.Error())
+		b.log.Error("validate-env", "could not find Jail directory: "+err.Error())
 		valid = false
 	}
 
-	// Validate GOROOT exists
-	_, err = b.term.Exec("ls " + b.BuildConfig.GoRoot)
-	if err != nil {
-		fmt.Println("Could not find GOROOT directory: " + err.Error())
-		valid = false
-	}
+	if ct, ok := b.term.(*ContainerTerminal); ok {
+		// The Go toolchain comes from the container image; what we need
+		// on the host is docker itself.
+		_, err = ct.host.Exec("which docker")
+		if err != nil {
+			b.log.Error("validate-env", "could not find docker binary: "+err.Error())
+			valid = false
+		}
+	} else {
+		// Validate GOROOT exists
+		_, err = b.term.Exec("ls " + b.BuildConfig.GoRoot)
+		if err != nil {
+			b.log.Error("validate-env", "could not find GOROOT directory: "+err.Error())
+			valid = false
+		}
 
-	// Validate Go Binary exists
-	_, err = b.term.Exec("ls " + b.BuildConfig.GoRoot + "/bin/go")
-	if err != nil {
-		fmt.Println("Could not find Go binary: " + err.Error())
-		valid = false
+		// Validate Go Binary exists
+		_, err = b.term.Exec("ls " + b.BuildConfig.GoRoot + "/bin/go")
+		if err != nil {
+			b.log.Error("validate-env", "could not find Go binary: "+err.Error())
+			valid = false
+		}
 	}
 
 	// Validate Git exists
 	_, err = b.term.Exec("which " + b.scm.BinaryName())
 	if err != nil {
-		fmt.Println("Could not find " + b.BuildConfig.RepoType + " binary: " + err.Error())
+		b.log.Error("validate-env", "could not find "+b.BuildConfig.RepoType+" binary: "+err.Error())
 		valid = false
 	}
 
@@ -170,47 +250,58 @@ func (b *builder) validateBuildEnvironment() (valid bool) {
 }
 
 // Checkout project from repository
-func (b *builder) updateCode() {
+func (b *builder) updateCode() error {
 	p, err := b.scm.ImportPathFromRepo(b.BuildConfig.AppRepo)
-	b.projectPath = path.Join(b.BuildConfig.Jail, "src", p)
-
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
+	b.projectPath = path.Join(b.BuildConfig.Jail, "src", p)
 
-	out, err := b.term.Exec("ls " + b.projectPath)
-
+	_, err = b.term.Exec("ls " + b.projectPath)
 	if err != nil {
-		fmt.Println("Creating project directories")
-		out, err = b.term.Exec("mkdir -p " + b.projectPath)
+		b.log.Info("checkout", "creating project directories")
 
-		if err != nil {
-			panic("Could not create project directories")
+		if _, err = b.term.Exec("mkdir -p " + b.projectPath); err != nil {
+			return fmt.Errorf("could not create project directories: %w", err)
 		}
-
-		fmt.Println(string(out))
 	}
 
 	// Fetch code base
 	b.scm.SetTerminal(b.term)
-	b.scm.Checkout(b.BuildConfig.AppRepo, b.BuildConfig.RepoBranch, b.projectPath)
-}
+	sha, err := b.scm.Checkout(b.BuildConfig.AppRepo, b.BuildConfig.RepoBranch, b.projectPath)
+	b.commitSHA = sha
 
-func (b *builder) setupScm() {
-	switch b.BuildConfig.RepoType {
-	case "git":
-		b.scm = new(GitScm)
+	return err
+}
 
-	default:
-		panic("unkown RepoType")
+func (b *builder) setupScm() error {
+	scm, err := scmForRepo(b.BuildConfig.RepoType, b.BuildConfig.AppRepo)
+	if err != nil {
+		return err
 	}
+
+	b.scm = scm
+	return nil
 }
 
-func (b *builder) updateDependencies() {
-	b.getPackageDependencies(path.Join(b.projectPath, b.BuildConfig.AppPath))
+func (b *builder) updateDependencies() error {
+	p := path.Join(b.projectPath, b.BuildConfig.AppPath)
+
+	if !b.BuildConfig.UpdatePackages {
+		if modules, err := b.readLockfile(); err == nil {
+			b.log.Info("dependencies", "UpdatePackages is false and a lockfile exists, resolving pinned versions without network access")
+			return b.restoreFromLockfile(p, modules)
+		}
+	}
+
+	if err := b.getPackageDependencies(p); err != nil {
+		return err
+	}
+
+	return b.writeLockfile(p)
 }
 
-func (b *builder) buildProject() {
+func (b *builder) buildProject() error {
 	p := path.Join(b.projectPath, b.BuildConfig.AppPath)
 	flags := "-v"
 
@@ -218,70 +309,99 @@ func (b *builder) buildProject() {
 		flags = flags + " -a"
 	}
 
-	fmt.Println("Building packages")
+	key, err := b.cacheKey(p)
+	if err != nil {
+		b.log.Warn("build-cache", "could not compute cache key, building without cache: "+err.Error())
+	} else if key != "" && !ForceRebuild && b.restoreFromCache(key) {
+		b.log.Info("build-cache", "cache hit for "+key+", skipping go install")
+		return nil
+	}
+
+	start := time.Now()
 	out, err := b.term.ExecPath("go install "+flags, p)
-	fmt.Println(string(out))
+	b.log.Step("build", b.BuildConfig.Host, "go install "+flags, out, err, time.Since(start))
 
 	if err != nil {
-		panic("Failed build: " + err.Error())
+		return fmt.Errorf("failed build: %w", err)
+	}
+
+	if key != "" {
+		if err := b.storeInCache(key); err != nil {
+			b.log.Warn("build-cache", "failed to store cache artifact: "+err.Error())
+		}
 	}
+
+	return nil
 }
 
-func (b *builder) runTests() {
+func (b *builder) runTests() error {
 	p := path.Join(b.projectPath, b.BuildConfig.AppPath)
 
-	fmt.Println("Testing packages")
+	start := time.Now()
 	out, err := b.term.ExecPath("go test", p)
-	fmt.Println(string(out))
+	b.log.Step("test", b.BuildConfig.Host, "go test", out, err, time.Since(start))
 
 	if err != nil {
-		panic("Failed tests: " + err.Error())
+		return fmt.Errorf("failed tests: %w", err)
 	}
 
 	if b.BuildConfig.TestSkynet {
-		b.testSkynet()
+		return b.testSkynet()
 	}
+
+	return nil
 }
 
-func (b *builder) testSkynet() {
-	fmt.Println("Testing Skynet")
+func (b *builder) testSkynet() error {
 	p := path.Join(b.BuildConfig.Jail, "src/github.com/skynetservices/skynet2")
 
-	b.getPackageDependencies(p)
+	if err := b.getPackageDependencies(p); err != nil {
+		return err
+	}
 
+	start := time.Now()
 	out, err := b.term.ExecPath("go test ./...", p)
-	fmt.Println(string(out))
+	b.log.Step("test-skynet", b.BuildConfig.Host, "go test ./...", out, err, time.Since(start))
 
 	if err != nil {
-		panic("Failed tests: " + err.Error())
+		return fmt.Errorf("failed tests: %w", err)
 	}
+
+	return nil
 }
 
-func (b *builder) getPackageDependencies(p string) {
+func (b *builder) getPackageDependencies(p string) error {
 	flags := []string{"-d"}
 
 	if b.BuildConfig.UpdatePackages {
 		flags = append(flags, "-u")
 	}
 
-	fmt.Println("Fetching dependencies")
-	out, err := b.term.ExecPath("go get "+strings.Join(flags, " ")+" ./...", p)
-	fmt.Println(string(out))
+	cmd := "go get " + strings.Join(flags, " ") + " ./..."
+
+	start := time.Now()
+	out, err := b.term.ExecPath(cmd, p)
+	b.log.Step("fetch-dependencies", b.BuildConfig.Host, cmd, out, err, time.Since(start))
 
 	if err != nil {
-		panic("Failed to fetch dependencies\n" + err.Error())
+		return fmt.Errorf("failed to fetch dependencies: %w", err)
 	}
+
+	return nil
 }
 
-func (b *builder) runCommands(cmds []string) {
+func (b *builder) runCommands(step string, cmds []string) error {
 	for _, cmd := range cmds {
+		start := time.Now()
 		out, err := b.term.Exec(cmd)
-		fmt.Println(string(out))
+		b.log.Step(step, b.BuildConfig.Host, cmd, out, err, time.Since(start))
 
 		if err != nil {
-			panic("Failed to execute dependent command: " + cmd + "\n" + err.Error())
+			return fmt.Errorf("failed to execute %s command %q: %w", step, cmd, err)
 		}
 	}
+
+	return nil
 }
 
 func (b *builder) goPath() string {
@@ -292,34 +412,6 @@ func (b *builder) goPath() string {
 	return b.BuildConfig.Jail
 }
 
-func (b *builder) deploy(hosts []string) {
-	for _, host := range hosts {
-		var out []byte
-		var err error
-
-		// TODO: if build and deploy boxes are remote, need to scp from server to server
-		if isHostLocal(host) && isHostLocal(b.BuildConfig.Host) {
-			fmt.Println("Copying local binary")
-			command := exec.Command("cp", path.Join(b.BuildConfig.Jail, "bin", path.Base(b.BuildConfig.AppPath)), path.Join(b.DeployConfig.DeployPath, b.DeployConfig.BinaryName))
-			out, err = command.CombinedOutput()
-		} else if isHostLocal(host) && !isHostLocal(b.BuildConfig.Host) {
-			// Deploying locally but build is remote
-			fmt.Println("Copying binary from build machine")
-			host, port := splitHostPort(b.BuildConfig.Host)
-
-			command := exec.Command("scp", "-P", port, b.BuildConfig.User+"@"+host+":"+path.Join(b.BuildConfig.Jail, "bin", path.Base(b.BuildConfig.AppPath)),
-				path.Join(b.DeployConfig.DeployPath, b.DeployConfig.BinaryName))
-			out, err = command.CombinedOutput()
-		}
-
-		fmt.Println(string(out))
-
-		if err != nil {
-			panic("Failed to deploy: " + err.Error())
-		}
-	}
-}
-
 func splitHostPort(host string) (string, string) {
 	parts := strings.Split(host, ":")
 
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BzrScm checks out a project tracked in Bazaar.
+type BzrScm struct {
+	term Terminal
+}
+
+func (b *BzrScm) SetTerminal(t Terminal) {
+	b.term = t
+}
+
+func (b *BzrScm) BinaryName() string {
+	return "bzr"
+}
+
+func (b *BzrScm) ImportPathFromRepo(repo string) (string, error) {
+	return importPathFromRepo(repo)
+}
+
+func (b *BzrScm) Checkout(repo, branch, path string) (string, error) {
+	_, err := b.term.ExecPath("bzr branch "+repo+" .", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to branch %s: %w", repo, err)
+	}
+
+	if branch != "" {
+		_, err = b.term.ExecPath("bzr switch "+branch, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to switch to %s: %w", branch, err)
+		}
+	}
+
+	out, err := b.term.ExecPath("bzr revno", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve revision: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ForceRebuild bypasses the build cache. It's a package-level var rather
+// than a Build argument so the CLI's --force-rebuild flag can set it
+// once before constructing a builder.
+var ForceRebuild bool
+
+const lockfileName = "skynet-lock.json"
+
+// moduleVersion is one entry of the lockfile skynet writes into the Jail
+// so a build can be reproduced offline when UpdatePackages is false.
+type moduleVersion struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+func (b *builder) lockfilePath() string {
+	return path.Join(b.BuildConfig.Jail, lockfileName)
+}
+
+func (b *builder) builtBinaryPath() string {
+	return path.Join(b.BuildConfig.Jail, "bin", path.Base(b.BuildConfig.AppPath))
+}
+
+// resolveModules runs `go list -m all` in the project so the exact
+// dependency graph can feed both the cache key and the lockfile.
+func (b *builder) resolveModules(p string) (modules []moduleVersion, raw string, err error) {
+	out, err := b.term.ExecPath("go list -m all", p)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw = strings.TrimSpace(string(out))
+
+	for _, line := range strings.Split(raw, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		modules = append(modules, moduleVersion{Module: fields[0], Version: fields[1]})
+	}
+
+	return modules, raw, nil
+}
+
+// writeLockfile records the resolved module versions into the Jail so
+// updateDependencies can pin to the same dependency graph on a later
+// build with UpdatePackages=false.
+func (b *builder) writeLockfile(p string) error {
+	modules, _, err := b.resolveModules(p)
+	if err != nil {
+		b.log.Warn("dependencies", "could not resolve module versions for lockfile: "+err.Error())
+		return nil
+	}
+
+	manifest, err := json.MarshalIndent(modules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = b.term.Exec("cat > " + b.lockfilePath() + " <<'SKYNET_LOCK_EOF'\n" + string(manifest) + "\nSKYNET_LOCK_EOF")
+	return err
+}
+
+// readLockfile parses the lockfile written by writeLockfile, if one
+// exists at lockfilePath.
+func (b *builder) readLockfile() ([]moduleVersion, error) {
+	out, err := b.term.Exec("cat " + b.lockfilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []moduleVersion
+	if err := json.Unmarshal(out, &modules); err != nil {
+		return nil, fmt.Errorf("could not parse lockfile %s: %w", b.lockfilePath(), err)
+	}
+
+	return modules, nil
+}
+
+// restoreFromLockfile resolves dependencies to the exact module versions
+// recorded in the lockfile with GOPROXY=off, so a build with
+// UpdatePackages=false reproduces the same dependency graph from the
+// local module cache instead of touching the network.
+func (b *builder) restoreFromLockfile(p string, modules []moduleVersion) error {
+	if len(modules) == 0 {
+		return nil
+	}
+
+	pins := make([]string, len(modules))
+	for i, m := range modules {
+		pins[i] = m.Module + "@" + m.Version
+	}
+
+	cmd := "GOPROXY=off go get -d " + strings.Join(pins, " ")
+
+	start := time.Now()
+	out, err := b.term.ExecPath(cmd, p)
+	b.log.Step("fetch-dependencies-lockfile", b.BuildConfig.Host, cmd, out, err, time.Since(start))
+
+	if err != nil {
+		return fmt.Errorf("failed to resolve dependencies from lockfile: %w", err)
+	}
+
+	return nil
+}
+
+// cacheKey fingerprints everything that affects the built binary: the
+// repo, the exact commit, the Go toolchain, cgo flags, and the resolved
+// dependency graph. Returns "" when caching isn't configured.
+func (b *builder) cacheKey(p string) (string, error) {
+	if b.BuildConfig.CacheDir == "" {
+		return "", nil
+	}
+
+	goVersion, err := b.term.Exec(b.BuildConfig.GoRoot + "/bin/go version")
+	if err != nil {
+		return "", err
+	}
+
+	_, depGraph, err := b.resolveModules(p)
+	if err != nil {
+		// go list -m all only works in module mode; treat the dependency
+		// graph as unresolved rather than failing the whole cache key.
+		depGraph = ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintln(h, b.BuildConfig.AppRepo)
+	fmt.Fprintln(h, b.commitSHA)
+	fmt.Fprintln(h, strings.TrimSpace(string(goVersion)))
+	fmt.Fprintln(h, b.BuildConfig.CgoCFlags)
+	fmt.Fprintln(h, b.BuildConfig.CgoLdFlags)
+	fmt.Fprintln(h, depGraph)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (b *builder) cacheArtifactPath(key string) string {
+	return path.Join(b.BuildConfig.CacheDir, key, b.DeployConfig.BinaryName)
+}
+
+// restoreFromCache copies a previously built binary for key into place,
+// if one exists, and reports whether it found one.
+func (b *builder) restoreFromCache(key string) bool {
+	cached := b.cacheArtifactPath(key)
+
+	if _, err := b.term.Exec("ls " + cached); err != nil {
+		return false
+	}
+
+	if _, err := b.term.Exec("cp " + cached + " " + b.builtBinaryPath()); err != nil {
+		b.log.Warn("build-cache", "found cached artifact but failed to restore it: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+// storeInCache saves the just-built binary under key for a future build
+// to reuse.
+func (b *builder) storeInCache(key string) error {
+	dir := path.Join(b.BuildConfig.CacheDir, key)
+
+	if _, err := b.term.Exec("mkdir -p " + dir); err != nil {
+		return err
+	}
+
+	_, err := b.term.Exec("cp " + b.builtBinaryPath() + " " + b.cacheArtifactPath(key))
+	return err
+}
@@ -0,0 +1,102 @@
+package main
+
+import "strings"
+
+// containerConfig describes the disposable container a build should run
+// inside, instead of whatever Go toolchain happens to be installed on
+// the build host.
+type containerConfig struct {
+	Image  string
+	Mounts []string // "host-path:container-path" pairs, docker -v syntax
+
+	// EnvPassthrough names host environment variables that should be
+	// forwarded into the container unchanged.
+	EnvPassthrough []string
+
+	Registry     string
+	RegistryUser string
+	RegistryPass string
+}
+
+// ContainerTerminal runs every command inside a disposable Docker/OCI
+// container on top of another Terminal (local or SSH), so builds happen
+// in a reproducible environment rather than against the build host's own
+// GOROOT/GOPATH.
+type ContainerTerminal struct {
+	host   Terminal
+	config containerConfig
+	mounts []string
+	env    []string
+
+	loggedIn bool
+}
+
+// NewContainerTerminal builds a ContainerTerminal that runs `docker` via
+// host. The Jail directory is always mounted at the same path inside the
+// container so build artifacts land where deploy expects to find them.
+func NewContainerTerminal(host Terminal, jail string, config containerConfig) *ContainerTerminal {
+	mounts := append([]string{jail + ":" + jail}, config.Mounts...)
+
+	return &ContainerTerminal{
+		host:   host,
+		config: config,
+		mounts: mounts,
+	}
+}
+
+func (c *ContainerTerminal) SetEnv(key, value string) {
+	c.env = append(c.env, key+"="+value)
+}
+
+func (c *ContainerTerminal) Exec(cmd string) ([]byte, error) {
+	return c.ExecPath(cmd, "")
+}
+
+func (c *ContainerTerminal) ExecPath(cmd string, path string) ([]byte, error) {
+	c.login()
+
+	args := []string{"run", "--rm"}
+
+	for _, m := range c.mounts {
+		args = append(args, "-v", m)
+	}
+
+	for _, e := range c.env {
+		args = append(args, "-e", e)
+	}
+
+	for _, name := range c.config.EnvPassthrough {
+		args = append(args, "-e", name)
+	}
+
+	if path != "" {
+		args = append(args, "-w", path)
+	}
+
+	args = append(args, c.config.Image, "sh", "-c", shellQuote(cmd))
+
+	return c.host.Exec("docker " + strings.Join(args, " "))
+}
+
+func (c *ContainerTerminal) Close() {
+	c.host.Close()
+}
+
+// login authenticates with the configured registry the first time the
+// container terminal is used, so Image can be pulled from a private repo.
+// The password is piped in via --password-stdin rather than passed as a
+// -p flag, so it never shows up in docker's own argv/cmdline.
+func (c *ContainerTerminal) login() {
+	if c.loggedIn || c.config.Registry == "" {
+		return
+	}
+
+	cmd := "docker login " + c.config.Registry + " -u " + c.config.RegistryUser +
+		" --password-stdin <<'SKYNET_REGISTRY_PASS_EOF'\n" + c.config.RegistryPass + "\nSKYNET_REGISTRY_PASS_EOF"
+	c.host.Exec(cmd)
+	c.loggedIn = true
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
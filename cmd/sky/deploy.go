@@ -0,0 +1,315 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDeployConcurrency = 4
+
+// deployResult captures the outcome of deploying to a single host so
+// callers get a structured summary instead of a panic on first failure.
+type deployResult struct {
+	Host    string
+	Success bool
+	Err     error
+}
+
+// deploy copies the built binary out to hosts concurrently, swaps it in
+// atomically on each one, and rolls back any host that already
+// succeeded if another host in the batch fails.
+func (b *builder) deploy(hosts []string) []deployResult {
+	concurrency := b.DeployConfig.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDeployConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	results := make([]deployResult, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+
+		go func(i int, host string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := b.deployToHost(host)
+			results[i] = deployResult{Host: host, Success: err == nil, Err: err}
+		}(i, host)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, r := range results {
+		if !r.Success {
+			failed = append(failed, r.Host)
+		}
+	}
+
+	if len(failed) > 0 {
+		b.log.Warn("deploy", "deploy failed on "+strings.Join(failed, ", ")+", rolling back succeeded hosts")
+
+		for _, r := range results {
+			if r.Success {
+				b.rollbackHost(r.Host)
+			}
+		}
+	}
+
+	return results
+}
+
+func (b *builder) binaryPath() string {
+	return path.Join(b.DeployConfig.DeployPath, b.DeployConfig.BinaryName)
+}
+
+// deployToHost copies the new binary and its checksum manifest(s)
+// alongside the live one, verifies them against what was just copied,
+// keeps the currently deployed binary and manifest(s) around as a
+// rollback target, then atomically swaps them all in with a rename.
+func (b *builder) deployToHost(host string) error {
+	binary := b.binaryPath()
+	manifest := binary + ".sha256"
+	newBinary := binary + ".new"
+	newManifest := manifest + ".new"
+	prevBinary := binary + ".prev"
+	prevManifest := manifest + ".prev"
+
+	sha512 := b.DeployConfig.Checksum.Sha512
+	manifest512 := binary + ".sha512"
+	newManifest512 := manifest512 + ".new"
+	prevManifest512 := manifest512 + ".prev"
+
+	sign := b.DeployConfig.Checksum.Sign
+	sig := binary + ".sig"
+	newSig := sig + ".new"
+	prevSig := sig + ".prev"
+
+	start := time.Now()
+	out, err := b.copyBinary(host, newBinary)
+	b.log.Step("deploy-copy", host, "copy "+binary, out, err, time.Since(start))
+
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+	out, err = b.copyManifest(host, newManifest)
+	b.log.Step("deploy-copy-manifest", host, "copy "+manifest, out, err, time.Since(start))
+
+	if err != nil {
+		return err
+	}
+
+	if sha512 {
+		start = time.Now()
+		out, err = b.copyFile(host, b.sha512ManifestPath(), newManifest512)
+		b.log.Step("deploy-copy-manifest-sha512", host, "copy "+manifest512, out, err, time.Since(start))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if sign {
+		start = time.Now()
+		out, err = b.copyFile(host, b.signaturePath(), newSig)
+		b.log.Step("deploy-copy-signature", host, "copy "+sig, out, err, time.Since(start))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	ok, err := b.verifyChecksum(host, newBinary, newManifest, "sha256sum")
+	if err != nil {
+		return fmt.Errorf("could not verify checksum on %s: %w", host, err)
+	}
+	if !ok {
+		return fmt.Errorf("checksum mismatch for %s on %s", newBinary, host)
+	}
+
+	if sha512 {
+		ok, err = b.verifyChecksum(host, newBinary, newManifest512, "sha512sum")
+		if err != nil {
+			return fmt.Errorf("could not verify sha512 checksum on %s: %w", host, err)
+		}
+		if !ok {
+			return fmt.Errorf("sha512 checksum mismatch for %s on %s", newBinary, host)
+		}
+	}
+
+	if sign {
+		if err := b.verifySignature(host, newManifest, newSig); err != nil {
+			return fmt.Errorf("could not verify signature on %s: %w", host, err)
+		}
+	}
+	b.log.Info("deploy-verify", "checksum verified on "+host)
+
+	// Best effort: there may not be a previously deployed binary yet.
+	b.execOnHost(host, "cp "+binary+" "+prevBinary)
+	b.execOnHost(host, "cp "+manifest+" "+prevManifest)
+	if sha512 {
+		b.execOnHost(host, "cp "+manifest512+" "+prevManifest512)
+	}
+	if sign {
+		b.execOnHost(host, "cp "+sig+" "+prevSig)
+	}
+
+	swapCmd := "mv " + newBinary + " " + binary + " && mv " + newManifest + " " + manifest
+	if sha512 {
+		swapCmd += " && mv " + newManifest512 + " " + manifest512
+	}
+	if sign {
+		swapCmd += " && mv " + newSig + " " + sig
+	}
+
+	start = time.Now()
+	out, err = b.execOnHost(host, swapCmd)
+	b.log.Step("deploy-swap", host, swapCmd, out, err, time.Since(start))
+
+	if err != nil {
+		// One of the renames in the chain may have already landed before
+		// a later one failed, leaving this host running an unverified
+		// binary while deployToHost reports failure. deploy()'s rollback
+		// loop only rolls back hosts it believes succeeded, so a host
+		// stuck here would otherwise never get rolled back. Roll back in
+		// place instead of leaving that to the caller.
+		b.log.Warn("deploy-swap", "swap failed on "+host+", rolling back in place: "+err.Error())
+		b.rollbackHost(host)
+		return err
+	}
+
+	return nil
+}
+
+func (b *builder) rollbackHost(host string) {
+	binary := b.binaryPath()
+	manifest := binary + ".sha256"
+	manifest512 := binary + ".sha512"
+	sig := binary + ".sig"
+	prevBinary := binary + ".prev"
+	prevManifest := manifest + ".prev"
+	prevManifest512 := manifest512 + ".prev"
+	prevSig := sig + ".prev"
+
+	rollbackCmd := "mv " + prevBinary + " " + binary + " && mv " + prevManifest + " " + manifest
+	if b.DeployConfig.Checksum.Sha512 {
+		// prevManifest512 may not exist yet if Sha512 was only just turned
+		// on, so don't let its absence fail the rest of the rollback.
+		rollbackCmd += " && { [ -f " + prevManifest512 + " ] && mv " + prevManifest512 + " " + manifest512 + " || true; }"
+	}
+	if b.DeployConfig.Checksum.Sign {
+		// Same reasoning for prevSig when Sign was only just turned on.
+		rollbackCmd += " && { [ -f " + prevSig + " ] && mv " + prevSig + " " + sig + " || true; }"
+	}
+
+	start := time.Now()
+	out, err := b.execOnHost(host, rollbackCmd)
+	b.log.Step("deploy-rollback", host, rollbackCmd, out, err, time.Since(start))
+
+	if err != nil {
+		b.log.Error("deploy-rollback", "failed to roll back "+host+": "+err.Error())
+	}
+}
+
+// copyFile gets a file from the build host onto the deploy host's
+// destination path, covering all four combinations of build host and
+// deploy host being local or remote.
+func (b *builder) copyFile(host, src, dest string) ([]byte, error) {
+	buildLocal := isHostLocal(b.BuildConfig.Host)
+	targetLocal := isHostLocal(host)
+
+	switch {
+	case buildLocal && targetLocal:
+		return exec.Command("cp", src, dest).CombinedOutput()
+
+	case !buildLocal && targetLocal:
+		buildHost, buildPort := splitHostPort(b.BuildConfig.Host)
+		return exec.Command("scp", "-P", buildPort, b.BuildConfig.User+"@"+buildHost+":"+src, dest).CombinedOutput()
+
+	case buildLocal && !targetLocal:
+		targetHost, targetPort := splitHostPort(host)
+		return exec.Command("scp", "-P", targetPort, src, b.DeployConfig.User+"@"+targetHost+":"+dest).CombinedOutput()
+
+	default:
+		return b.copyBinaryRemoteToRemote(host, src, dest)
+	}
+}
+
+// copyBinary gets the built binary from the build host onto the deploy
+// host's destination path.
+func (b *builder) copyBinary(host, dest string) ([]byte, error) {
+	return b.copyFile(host, b.builtBinaryPath(), dest)
+}
+
+// copyManifest gets the checksum manifest signArtifact wrote next to the
+// built binary onto the deploy host's destination path.
+func (b *builder) copyManifest(host, dest string) ([]byte, error) {
+	return b.copyFile(host, b.manifestPath(), dest)
+}
+
+// copyBinaryRemoteToRemote tries a direct server-to-server scp (-3 routes
+// the data through us, which works even when the two hosts can't reach
+// each other). If that fails, it falls back to streaming the binary
+// through the controller: build host -> here -> deploy host.
+func (b *builder) copyBinaryRemoteToRemote(host, src, dest string) ([]byte, error) {
+	buildHost, buildPort := splitHostPort(b.BuildConfig.Host)
+	targetHost, targetPort := splitHostPort(host)
+
+	if buildPort == targetPort {
+		direct := exec.Command("scp", "-3", "-P", buildPort,
+			b.BuildConfig.User+"@"+buildHost+":"+src,
+			b.DeployConfig.User+"@"+targetHost+":"+dest)
+
+		if out, err := direct.CombinedOutput(); err == nil {
+			return out, nil
+		}
+
+		b.log.Debug("deploy-copy", "direct scp between build and deploy host failed, streaming through controller")
+	} else {
+		// scp -3 only takes one -P for both ends of a direct transfer,
+		// so it can't represent build and deploy hosts on different
+		// ports; skip straight to the relay instead of attempting a
+		// transfer that would silently connect to the wrong port on
+		// one side.
+		b.log.Debug("deploy-copy", "build and deploy hosts use different SSH ports, streaming through controller")
+	}
+
+	tmp, err := ioutil.TempFile("", "skynet-deploy-")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	out, err := exec.Command("scp", "-P", buildPort, b.BuildConfig.User+"@"+buildHost+":"+src, tmp.Name()).CombinedOutput()
+	if err != nil {
+		return out, err
+	}
+
+	moreOut, err := exec.Command("scp", "-P", targetPort, tmp.Name(), b.DeployConfig.User+"@"+targetHost+":"+dest).CombinedOutput()
+
+	return append(out, moreOut...), err
+}
+
+// execOnHost runs cmd on a deploy target, independent of the builder's
+// own build-host Terminal.
+func (b *builder) execOnHost(host, cmd string) ([]byte, error) {
+	if isHostLocal(host) {
+		return exec.Command("sh", "-c", cmd).CombinedOutput()
+	}
+
+	targetHost, targetPort := splitHostPort(host)
+	return exec.Command("ssh", "-p", targetPort, b.DeployConfig.User+"@"+targetHost, cmd).CombinedOutput()
+}
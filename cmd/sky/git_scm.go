@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GitScm checks out a project tracked in Git.
+type GitScm struct {
+	term Terminal
+}
+
+func (g *GitScm) SetTerminal(t Terminal) {
+	g.term = t
+}
+
+func (g *GitScm) BinaryName() string {
+	return "git"
+}
+
+func (g *GitScm) ImportPathFromRepo(repo string) (string, error) {
+	return importPathFromRepo(repo)
+}
+
+func (g *GitScm) Checkout(repo, branch, path string) (string, error) {
+	_, err := g.term.ExecPath("git clone "+repo+" .", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", repo, err)
+	}
+
+	if branch != "" {
+		_, err = g.term.ExecPath("git checkout "+branch, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to checkout %s: %w", branch, err)
+		}
+	}
+
+	out, err := g.term.ExecPath("git rev-parse HEAD", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HgScm checks out a project tracked in Mercurial.
+type HgScm struct {
+	term Terminal
+}
+
+func (h *HgScm) SetTerminal(t Terminal) {
+	h.term = t
+}
+
+func (h *HgScm) BinaryName() string {
+	return "hg"
+}
+
+func (h *HgScm) ImportPathFromRepo(repo string) (string, error) {
+	return importPathFromRepo(repo)
+}
+
+func (h *HgScm) Checkout(repo, branch, path string) (string, error) {
+	_, err := h.term.ExecPath("hg clone "+repo+" .", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", repo, err)
+	}
+
+	if branch != "" {
+		_, err = h.term.ExecPath("hg update "+branch, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to update to %s: %w", branch, err)
+		}
+	}
+
+	out, err := h.term.ExecPath("hg id -i", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve revision: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
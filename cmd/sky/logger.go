@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// LogFormat selects how a Logger renders entries. It's a package-level
+// var rather than a constructor argument so the CLI's --log-format flag
+// can set it once before Build/Deploy construct their builder.
+var LogFormat = "text"
+
+const (
+	levelDebug = "debug"
+	levelInfo  = "info"
+	levelWarn  = "warn"
+	levelError = "error"
+)
+
+// logEntry is what actually gets rendered, in both the text and JSON
+// sinks, so the two stay in sync.
+type logEntry struct {
+	Time          string `json:"time"`
+	Level         string `json:"level"`
+	CorrelationID string `json:"correlation_id"`
+	Step          string `json:"step,omitempty"`
+	Host          string `json:"host,omitempty"`
+	Command       string `json:"command,omitempty"`
+	ExitCode      int    `json:"exit_code,omitempty"`
+	Stdout        string `json:"stdout,omitempty"`
+	Stderr        string `json:"stderr,omitempty"`
+	DurationMs    int64  `json:"duration_ms,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
+
+// Logger is a small leveled logger with a text sink (for humans watching
+// a terminal) and a JSON sink (for CI consumers parsing build results).
+type Logger struct {
+	Format        string
+	Writer        io.Writer
+	CorrelationID string
+}
+
+// NewLogger builds a Logger using the given format ("text" or "json"),
+// tagging every entry it writes with a fresh correlation id so a single
+// build's log lines can be grouped together downstream.
+func NewLogger(format string) *Logger {
+	return &Logger{
+		Format:        format,
+		Writer:        os.Stdout,
+		CorrelationID: newCorrelationID(),
+	}
+}
+
+func newCorrelationID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func (l *Logger) Debug(step, message string) { l.write(levelDebug, step, message, nil) }
+func (l *Logger) Info(step, message string)  { l.write(levelInfo, step, message, nil) }
+func (l *Logger) Warn(step, message string)  { l.write(levelWarn, step, message, nil) }
+func (l *Logger) Error(step, message string) { l.write(levelError, step, message, nil) }
+
+// Step logs the result of running command on host as part of step,
+// recording its exit code, output, and how long it took.
+func (l *Logger) Step(step, host, command string, out []byte, err error, duration time.Duration) {
+	exitCode := 0
+	level := levelInfo
+	message := step + " succeeded"
+
+	if err != nil {
+		level = levelError
+		exitCode = 1
+		message = step + " failed: " + err.Error()
+
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+
+	l.write(level, step, message, func(e *logEntry) {
+		e.Host = host
+		e.Command = command
+		e.ExitCode = exitCode
+		e.Stdout = string(out)
+		e.DurationMs = duration.Milliseconds()
+	})
+}
+
+func (l *Logger) write(level, step, message string, decorate func(*logEntry)) {
+	e := logEntry{
+		Time:          time.Now().Format(time.RFC3339),
+		Level:         level,
+		CorrelationID: l.CorrelationID,
+		Step:          step,
+		Message:       message,
+	}
+
+	if decorate != nil {
+		decorate(&e)
+	}
+
+	if l.Format == "json" {
+		b, _ := json.Marshal(e)
+		fmt.Fprintln(l.Writer, string(b))
+		return
+	}
+
+	fmt.Fprintln(l.Writer, e.text())
+}
+
+func (e logEntry) text() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[%s] %-5s %s", e.Time, strings.ToUpper(e.Level), e.Step)
+
+	if e.Host != "" {
+		fmt.Fprintf(&b, " host=%s", e.Host)
+	}
+	if e.Command != "" {
+		fmt.Fprintf(&b, " cmd=%q", e.Command)
+	}
+	if e.DurationMs > 0 {
+		fmt.Fprintf(&b, " duration=%dms", e.DurationMs)
+	}
+
+	fmt.Fprintf(&b, " %s", e.Message)
+
+	if e.Stdout != "" {
+		fmt.Fprintf(&b, "\n%s", e.Stdout)
+	}
+
+	return b.String()
+}
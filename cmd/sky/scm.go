@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Scm is the interface skynet uses to fetch a project's source before
+// building it. Implementations wrap whatever command line tool talks to
+// the actual version control system.
+type Scm interface {
+	SetTerminal(t Terminal)
+	// Checkout fetches repo at branch into path and returns the resolved
+	// revision (e.g. a Git commit SHA) so callers can key a build cache
+	// off the exact code that was checked out.
+	Checkout(repo, branch, path string) (string, error)
+	ImportPathFromRepo(repo string) (string, error)
+	BinaryName() string
+}
+
+// scmForRepo picks the right Scm for AppRepo, either from an explicit
+// RepoType or, when RepoType is empty, by probing the repo the way
+// go/vcs's RepoRootForImportPath does: known hosting patterns first,
+// falling back to <meta name="go-import"> discovery over HTTPS.
+func scmForRepo(repoType, appRepo string) (Scm, error) {
+	switch repoType {
+	case "git":
+		return new(GitScm), nil
+	case "hg":
+		return new(HgScm), nil
+	case "svn":
+		return new(SvnScm), nil
+	case "bzr":
+		return new(BzrScm), nil
+	case "":
+		if s := detectScm(appRepo); s != nil {
+			return s, nil
+		}
+
+		return nil, errors.New("could not detect RepoType for " + appRepo)
+	default:
+		return nil, errors.New("unkown RepoType: " + repoType)
+	}
+}
+
+var scmHostPatterns = []struct {
+	host string
+	scm  func() Scm
+}{
+	{"github.com", func() Scm { return new(GitScm) }},
+	// Bitbucket dropped Mercurial hosting in 2016; virtually every
+	// bitbucket.org repo today is Git, so default there instead of Hg.
+	{"bitbucket.org", func() Scm { return new(GitScm) }},
+	{"launchpad.net", func() Scm { return new(BzrScm) }},
+}
+
+// metaImport mirrors the <meta name="go-import" content="prefix vcs repo">
+// tag go/vcs looks for when a repo isn't hosted on a well known site.
+var metaImportRe = regexp.MustCompile(`<meta\s+name=["']go-import["']\s+content=["']([^"']+)["']\s*/?>`)
+
+// detectScm returns the Scm implementation appropriate for appRepo, or
+// nil if it couldn't be determined.
+func detectScm(appRepo string) Scm {
+	host := repoHost(appRepo)
+
+	for _, pattern := range scmHostPatterns {
+		if host == pattern.host || strings.HasSuffix(host, "."+pattern.host) {
+			return pattern.scm()
+		}
+	}
+
+	return metaImportScm(appRepo)
+}
+
+// importPathFromRepo strips the scheme and a trailing .git/.bzr/.hg
+// suffix from a repo URL, giving the import path it should be checked
+// out under inside the Jail (e.g. "github.com/foo/bar").
+func importPathFromRepo(appRepo string) (string, error) {
+	path := appRepo
+	path = strings.TrimPrefix(path, "https://")
+	path = strings.TrimPrefix(path, "http://")
+	path = strings.TrimPrefix(path, "ssh://")
+
+	path = strings.TrimSuffix(path, ".git")
+	path = strings.TrimSuffix(path, ".bzr")
+	path = strings.TrimSuffix(path, ".hg")
+
+	if path == "" {
+		return "", errNotAUrl(appRepo)
+	}
+
+	return path, nil
+}
+
+type errNotAUrl string
+
+func (e errNotAUrl) Error() string {
+	return "not a repo URL: " + string(e)
+}
+
+func repoHost(appRepo string) string {
+	repo := appRepo
+	repo = strings.TrimPrefix(repo, "https://")
+	repo = strings.TrimPrefix(repo, "http://")
+
+	if i := strings.IndexAny(repo, "/:"); i != -1 {
+		repo = repo[:i]
+	}
+
+	return repo
+}
+
+// metaImportHTTPTimeout bounds the go-import discovery request below so a
+// slow or unresponsive custom-domain host can't hang a build indefinitely.
+const metaImportHTTPTimeout = 10 * time.Second
+
+var metaImportHTTPClient = &http.Client{Timeout: metaImportHTTPTimeout}
+
+// metaImportScm fetches "https://<host>/<path>?go-get=1" and parses the
+// go-import meta tag to learn which vcs serves the repo, the same
+// discovery go/vcs performs for custom import paths.
+func metaImportScm(appRepo string) Scm {
+	url := "https://" + strings.TrimPrefix(strings.TrimPrefix(appRepo, "https://"), "http://") + "?go-get=1"
+
+	resp, err := metaImportHTTPClient.Get(url)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil
+	}
+
+	match := metaImportRe.FindStringSubmatch(string(body))
+	if match == nil {
+		return nil
+	}
+
+	fields := strings.Fields(match[1])
+	if len(fields) != 3 {
+		return nil
+	}
+
+	switch fields[1] {
+	case "git":
+		return new(GitScm)
+	case "hg":
+		return new(HgScm)
+	case "svn":
+		return new(SvnScm)
+	case "bzr":
+		return new(BzrScm)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checksumConfig controls the integrity manifest skynet writes next to
+// every binary it builds, and optionally a detached signature over it.
+type checksumConfig struct {
+	Sha512 bool
+
+	Sign       bool
+	UseGPG     bool
+	KeyID      string // gpg --local-user id, used when UseGPG
+	KeyPath    string // Ed25519 private key PEM, used to sign when !UseGPG
+	PubKeyPath string // Ed25519 public key PEM, used to verify on deploy hosts when !UseGPG
+}
+
+func (b *builder) manifestPath() string {
+	return b.builtBinaryPath() + ".sha256"
+}
+
+func (b *builder) sha512ManifestPath() string {
+	return b.builtBinaryPath() + ".sha512"
+}
+
+func (b *builder) signaturePath() string {
+	return b.builtBinaryPath() + ".sig"
+}
+
+// signArtifact writes a checksum manifest for the built binary and,
+// if configured, a detached signature over it.
+func (b *builder) signArtifact() error {
+	binary := b.builtBinaryPath()
+
+	out, err := b.term.Exec("sha256sum " + binary + " > " + b.manifestPath())
+	b.log.Step("checksum", b.BuildConfig.Host, "sha256sum "+binary, out, err, 0)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s: %w", binary, err)
+	}
+
+	if b.DeployConfig.Checksum.Sha512 {
+		out, err = b.term.Exec("sha512sum " + binary + " > " + b.sha512ManifestPath())
+		b.log.Step("checksum-sha512", b.BuildConfig.Host, "sha512sum "+binary, out, err, 0)
+		if err != nil {
+			return fmt.Errorf("failed to sha512 checksum %s: %w", binary, err)
+		}
+	}
+
+	if !b.DeployConfig.Checksum.Sign {
+		return nil
+	}
+
+	return b.signManifest()
+}
+
+func (b *builder) signManifest() error {
+	cfg := b.DeployConfig.Checksum
+
+	var cmd string
+	if cfg.UseGPG {
+		cmd = "gpg --batch --yes --local-user " + cfg.KeyID + " --detach-sign --armor -o " + b.signaturePath() + " " + b.manifestPath()
+	} else {
+		cmd = "openssl pkeyutl -sign -inkey " + cfg.KeyPath + " -rawin -in " + b.manifestPath() + " -out " + b.signaturePath()
+	}
+
+	out, err := b.term.Exec(cmd)
+	b.log.Step("sign", b.BuildConfig.Host, cmd, out, err, 0)
+	if err != nil {
+		return fmt.Errorf("failed to sign manifest: %w", err)
+	}
+
+	return nil
+}
+
+// verifyChecksum reads the manifest at manifestPath on host, produced by
+// sumCmd (e.g. "sha256sum" or "sha512sum"), and confirms it matches the
+// current contents of binary.
+func (b *builder) verifyChecksum(host, binary, manifestPath, sumCmd string) (bool, error) {
+	manifestOut, err := b.execOnHost(host, "cat "+manifestPath)
+	if err != nil {
+		return false, fmt.Errorf("could not read manifest %s on %s: %w", manifestPath, host, err)
+	}
+
+	fields := strings.Fields(string(manifestOut))
+	if len(fields) == 0 {
+		return false, fmt.Errorf("manifest %s on %s is empty", manifestPath, host)
+	}
+	expected := fields[0]
+
+	sumOut, err := b.execOnHost(host, sumCmd+" "+binary)
+	if err != nil {
+		return false, fmt.Errorf("could not checksum %s on %s: %w", binary, host, err)
+	}
+
+	actualFields := strings.Fields(string(sumOut))
+	if len(actualFields) == 0 {
+		return false, fmt.Errorf("%s produced no output for %s on %s", sumCmd, binary, host)
+	}
+
+	return expected == actualFields[0], nil
+}
+
+// verifySignature checks the detached signature at sigPath on host against
+// manifestPath, using the same key material/tooling signManifest signed
+// with.
+func (b *builder) verifySignature(host, manifestPath, sigPath string) error {
+	cfg := b.DeployConfig.Checksum
+
+	var cmd string
+	if cfg.UseGPG {
+		cmd = "gpg --batch --verify " + sigPath + " " + manifestPath
+	} else {
+		cmd = "openssl pkeyutl -verify -pubin -inkey " + cfg.PubKeyPath + " -rawin -in " + manifestPath + " -sigfile " + sigPath
+	}
+
+	out, err := b.execOnHost(host, cmd)
+	if err != nil {
+		return fmt.Errorf("signature verification failed on %s: %w (%s)", host, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Verify re-checks every deployed binary against its checksum manifest
+// (and, if Sha512 is configured, its sha512 manifest, and if Sign is
+// configured, its detached signature too), without building or deploying
+// anything.
+func Verify(config string) error {
+	b, err := newBuilder(config)
+	if err != nil {
+		return err
+	}
+	defer b.term.Close()
+
+	hosts := b.DeployConfig.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+
+	var failed []string
+	for _, host := range hosts {
+		ok, err := b.verifyChecksum(host, b.binaryPath(), b.binaryPath()+".sha256", "sha256sum")
+
+		if err == nil && ok && b.DeployConfig.Checksum.Sha512 {
+			ok, err = b.verifyChecksum(host, b.binaryPath(), b.binaryPath()+".sha512", "sha512sum")
+		}
+
+		if err == nil && ok && b.DeployConfig.Checksum.Sign {
+			err = b.verifySignature(host, b.binaryPath()+".sha256", b.binaryPath()+".sig")
+		}
+
+		switch {
+		case err != nil:
+			b.log.Error("verify", "failed to verify "+host+": "+err.Error())
+			failed = append(failed, host)
+		case !ok:
+			b.log.Error("verify", "checksum mismatch on "+host)
+			failed = append(failed, host)
+		default:
+			b.log.Info("verify", "checksum verified on "+host)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("verification failed on: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// SSHConn runs commands on a remote host over the system ssh binary.
+type SSHConn struct {
+	Host string
+	User string
+
+	env []string
+}
+
+func (s *SSHConn) Connect(host, user string) {
+	s.Host = host
+	s.User = user
+}
+
+func (s *SSHConn) SetEnv(key, value string) {
+	s.env = append(s.env, key+"="+value)
+}
+
+func (s *SSHConn) Exec(cmd string) ([]byte, error) {
+	return s.ExecPath(cmd, "")
+}
+
+func (s *SSHConn) ExecPath(cmd string, path string) ([]byte, error) {
+	host, port := splitHostPort(s.Host)
+
+	remote := cmd
+	if path != "" {
+		remote = "cd " + path + " && " + remote
+	}
+
+	if len(s.env) > 0 {
+		remote = strings.Join(s.env, " ") + " " + remote
+	}
+
+	command := exec.Command("ssh", "-p", port, s.User+"@"+host, remote)
+
+	return command.CombinedOutput()
+}
+
+func (s *SSHConn) Close() {
+	// Nothing to tear down, we shell out per command
+}
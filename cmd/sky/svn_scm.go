@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SvnScm checks out a project tracked in Subversion. Subversion has no
+// separate clone/checkout step and branches are just paths within the
+// repository, so branch is appended to the checkout URL when present.
+type SvnScm struct {
+	term Terminal
+}
+
+func (s *SvnScm) SetTerminal(t Terminal) {
+	s.term = t
+}
+
+func (s *SvnScm) BinaryName() string {
+	return "svn"
+}
+
+func (s *SvnScm) ImportPathFromRepo(repo string) (string, error) {
+	return importPathFromRepo(repo)
+}
+
+func (s *SvnScm) Checkout(repo, branch, path string) (string, error) {
+	url := repo
+	if branch != "" {
+		url = repo + "/" + branch
+	}
+
+	_, err := s.term.ExecPath("svn checkout "+url+" .", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to checkout %s: %w", repo, err)
+	}
+
+	out, err := s.term.ExecPath("svnversion .", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve revision: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Terminal abstracts command execution so the builder can run the same
+// build steps locally or against a remote host.
+type Terminal interface {
+	Exec(cmd string) ([]byte, error)
+	ExecPath(cmd string, path string) ([]byte, error)
+	SetEnv(key, value string)
+	Close()
+}
+
+// LocalTerminal runs commands on the machine skynet itself is running on.
+type LocalTerminal struct {
+	env []string
+}
+
+func (t *LocalTerminal) SetEnv(key, value string) {
+	t.env = append(t.env, key+"="+value)
+}
+
+func (t *LocalTerminal) Exec(cmd string) ([]byte, error) {
+	return t.ExecPath(cmd, "")
+}
+
+func (t *LocalTerminal) ExecPath(cmd string, path string) ([]byte, error) {
+	c := exec.Command("sh", "-c", cmd)
+	c.Dir = path
+	c.Env = append(os.Environ(), t.env...)
+
+	return c.CombinedOutput()
+}
+
+func (t *LocalTerminal) Close() {
+	// Nothing to tear down for a local terminal
+}